@@ -0,0 +1,177 @@
+package integrationtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// killGrace is how long binHandler waits after sending a graceful
+// termination signal before forcibly killing the test binary.
+const killGrace = 5 * time.Second
+
+// RunResult describes how the test binary's run ended, for assertions in
+// the wrapped test suite.
+type RunResult struct {
+	// ExitCode is the test binary's exit code, or -1 if it was killed by a
+	// signal.
+	ExitCode int
+	// Killed reports whether the binary had to be killed, either because
+	// OptRunTimeout's deadline was exceeded or it ignored termination.
+	Killed bool
+	// PeakRSS is the peak resident set size in platform-dependent units
+	// (KB on Linux), or 0 if it could not be determined.
+	PeakRSS int64
+	// Duration is the time from starting the binary until Stop finished
+	// terminating it; a binary that exited on its own earlier is still
+	// counted until Stop.
+	Duration time.Duration
+}
+
+// binHandler compiles the target package into a binary and supervises
+// running it as a child process for the duration of the integration test.
+type binHandler struct {
+	base   string
+	target string
+	bin    string
+
+	runArgs   []string
+	buildArgs []string
+	runEnv    []string
+	buildEnv  []string
+
+	coverDir string
+
+	runTimeout    time.Duration
+	memoryLimitMB int
+	cpuLimitSec   int
+	cgroupName    string
+	stdin         io.Reader
+
+	cmd       *exec.Cmd
+	startedAt time.Time
+	timer     *time.Timer
+	timedOut  bool
+	// forceKilled is set by doTerminate when SIGTERM didn't make the child
+	// exit within killGrace and SIGKILL had to be used.
+	forceKilled bool
+
+	terminateOnce sync.Once
+	terminateErr  error
+
+	result RunResult
+}
+
+func (b *binHandler) build() error {
+	if b.target == "" {
+		return nil
+	}
+
+	if b.bin == "" {
+		b.bin = filepath.Join(os.TempDir(), "integrationtest-bin")
+	}
+
+	args := append([]string{"build", "-o", b.bin}, b.buildArgs...)
+	args = append(args, b.target)
+
+	cmd := exec.Command("go", args...) //nolint:gosec
+	cmd.Dir = b.base
+	cmd.Env = append(os.Environ(), b.buildEnv...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build failed: %w", err)
+	}
+	return nil
+}
+
+func (b *binHandler) run() error {
+	if b.bin == "" {
+		return nil
+	}
+
+	name, args := b.bin, b.runArgs
+	if b.memoryLimitMB > 0 || b.cpuLimitSec > 0 {
+		name, args = wrapWithRlimits(b.bin, b.runArgs, b.memoryLimitMB, b.cpuLimitSec)
+	}
+
+	cmd := exec.Command(name, args...) //nolint:gosec
+	cmd.Dir = b.base
+	cmd.Env = append(os.Environ(), b.runEnv...)
+	if b.coverDir != "" {
+		cmd.Env = append(cmd.Env, "GOCOVERDIR="+b.coverDir)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if b.stdin != nil {
+		cmd.Stdin = b.stdin
+	}
+	setSysProcAttr(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s failed: %w", b.bin, err)
+	}
+
+	b.cmd = cmd
+	b.startedAt = time.Now()
+
+	if b.cgroupName != "" {
+		if err := addToCgroup(b.cgroupName, cmd.Process.Pid, b.memoryLimitMB); err != nil {
+			return fmt.Errorf("placing %s in cgroup %q failed: %w", b.bin, b.cgroupName, err)
+		}
+	}
+
+	if b.runTimeout > 0 {
+		b.timer = time.AfterFunc(b.runTimeout, func() {
+			b.timedOut = true
+			_ = b.terminate()
+		})
+	}
+
+	return nil
+}
+
+func (b *binHandler) stop() error {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return nil
+	}
+
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	err := b.terminate()
+
+	if b.cmd.ProcessState != nil {
+		b.result = RunResult{
+			ExitCode: b.cmd.ProcessState.ExitCode(),
+			Killed:   b.timedOut || b.forceKilled,
+			PeakRSS:  peakRSS(b.cmd.ProcessState),
+			Duration: time.Since(b.startedAt),
+		}
+	}
+
+	if b.cgroupName != "" {
+		cleanupCgroup(b.cgroupName)
+	}
+
+	if err != nil {
+		return fmt.Errorf("killing %s failed: %w", b.bin, err)
+	}
+	return nil
+}
+
+// terminate sends a graceful termination signal to the child process and
+// escalates to a forceful kill after killGrace, running at most once
+// regardless of whether it was triggered by OptRunTimeout or by Stop.
+func (b *binHandler) terminate() error {
+	b.terminateOnce.Do(func() {
+		b.terminateErr = b.doTerminate()
+	})
+	return b.terminateErr
+}
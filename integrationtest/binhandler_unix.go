@@ -0,0 +1,81 @@
+//go:build unix
+
+package integrationtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setSysProcAttr puts the child in its own process group so terminate can
+// signal it (and anything it forked, e.g. the ulimit shell wrapper used by
+// wrapWithRlimits) as a unit.
+func setSysProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// wrapWithRlimits re-points the command at a shell that applies RLIMIT_AS
+// (via `ulimit -v`, in KB) and RLIMIT_CPU (via `ulimit -t`, in seconds)
+// before exec-ing the real binary. The standard library's os/exec has no
+// pre-exec hook for calling syscall.Setrlimit in the child between fork
+// and exec, so a thin shell preamble is used instead, the same trick
+// sandboxes like the Go playground's rely on.
+func wrapWithRlimits(bin string, args []string, memoryLimitMB, cpuLimitSec int) (string, []string) {
+	script := ""
+	if memoryLimitMB > 0 {
+		script += fmt.Sprintf("ulimit -v %d; ", memoryLimitMB*1024)
+	}
+	if cpuLimitSec > 0 {
+		script += fmt.Sprintf("ulimit -t %d; ", cpuLimitSec)
+	}
+	script += `exec "$0" "$@"`
+
+	return "/bin/sh", append([]string{"-c", script, bin}, args...)
+}
+
+// doTerminate signals the child's process group with SIGTERM, waits up to
+// killGrace for it to exit and escalates to SIGKILL if it hasn't.
+func (b *binHandler) doTerminate() error {
+	pgid, err := syscall.Getpgid(b.cmd.Process.Pid)
+	if err != nil {
+		pgid = b.cmd.Process.Pid
+	}
+
+	waitDone := make(chan struct{})
+	go func() {
+		_ = b.cmd.Wait()
+		close(waitDone)
+	}()
+
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("sending SIGTERM failed: %w", err)
+	}
+
+	select {
+	case <-waitDone:
+		return nil
+	case <-time.After(killGrace):
+	}
+
+	b.forceKilled = true
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("sending SIGKILL failed: %w", err)
+	}
+
+	<-waitDone
+	return nil
+}
+
+// peakRSS reads the peak resident set size from the child's rusage, in the
+// units reported by the kernel (KB on Linux, bytes on Darwin).
+func peakRSS(state *os.ProcessState) int64 {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return 0
+	}
+	return int64(ru.Maxrss)
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package integrationtest
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setSysProcAttr is a no-op on Windows; there is no process group concept
+// to opt into here.
+func setSysProcAttr(_ *exec.Cmd) {}
+
+// wrapWithRlimits is unsupported on Windows: syscall.Setrlimit and ulimit
+// have no equivalent, so OptMemoryLimitMB/OptCPULimit are ignored.
+func wrapWithRlimits(bin string, args []string, _, _ int) (string, []string) {
+	return bin, args
+}
+
+// doTerminate falls back to a hard kill; Windows has no SIGTERM to give
+// the child a chance to shut down gracefully first.
+func (b *binHandler) doTerminate() error {
+	b.forceKilled = true
+	if err := b.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	_ = b.cmd.Wait()
+	return nil
+}
+
+// peakRSS is unsupported on Windows; os.ProcessState carries no rusage
+// there.
+func peakRSS(_ *os.ProcessState) int64 {
+	return 0
+}
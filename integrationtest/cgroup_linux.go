@@ -0,0 +1,56 @@
+//go:build linux
+
+package integrationtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// cgroupRoot is the standard cgroup v2 mount point on modern Linux
+// systems.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// addToCgroup creates a transient cgroup v2 child under cgroupRoot named
+// name, caps its memory.max at memoryLimitMB (0 leaves it unbounded) and
+// moves pid into it, so memory pressure from the system under test is
+// actually contained instead of risking an OOM kill of the whole test
+// host. The caller is expected to pass the cgroup name to cleanupCgroup
+// once the child has exited.
+func addToCgroup(name string, pid, memoryLimitMB int) error {
+	// memory.max on the child has no effect until the memory controller is
+	// enabled on cgroupRoot's subtree_control; writing "+memory" is a
+	// no-op if it's already enabled there, so a failure here is ignored
+	// and surfaces later as a clearer memory.max write error instead.
+	_ = os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory"), 0o644) //nolint:gosec
+
+	dir := filepath.Join(cgroupRoot, name)
+	if err := os.Mkdir(dir, 0o755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("creating cgroup %q failed: %w", dir, err)
+	}
+
+	if memoryLimitMB > 0 {
+		maxPath := filepath.Join(dir, "memory.max")
+		limit := strconv.FormatInt(int64(memoryLimitMB)*1024*1024, 10)
+		if err := os.WriteFile(maxPath, []byte(limit), 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("capping %s at %dMB failed: %w", maxPath, memoryLimitMB, err)
+		}
+	}
+
+	procs := filepath.Join(dir, "cgroup.procs")
+	if err := os.WriteFile(procs, []byte(strconv.Itoa(pid)), 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("writing pid to %s failed: %w", procs, err)
+	}
+
+	return nil
+}
+
+// cleanupCgroup removes the transient cgroup created by addToCgroup. It is
+// best-effort: the kernel refuses to remove a cgroup while it still has
+// live processes, which is harmless here since the child has already been
+// waited on by the time Stop calls this.
+func cleanupCgroup(name string) {
+	_ = os.Remove(filepath.Join(cgroupRoot, name))
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package integrationtest
+
+import "errors"
+
+// addToCgroup reports that cgroups are unsupported outside Linux rather
+// than silently ignoring OptCgroup.
+func addToCgroup(_ string, _, _ int) error {
+	return errors.New("OptCgroup is only supported on Linux")
+}
+
+// cleanupCgroup is a no-op on platforms where addToCgroup always fails.
+func cleanupCgroup(_ string) {}
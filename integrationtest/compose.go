@@ -0,0 +1,35 @@
+package integrationtest
+
+import (
+	"context"
+	"fmt"
+
+	tc "github.com/testcontainers/testcontainers-go/modules/compose"
+)
+
+// ComposeOpt configures the docker compose stack registered via OptCompose.
+type ComposeOpt func(*composeHandler)
+
+// composeHandler brings up and tears down a docker compose stack as a
+// Handler in the IntegrationTestRunner's pre handler chain.
+type composeHandler struct {
+	c tc.ComposeStack
+}
+
+func (c *composeHandler) Start(ctx context.Context) error {
+	if err := c.c.Up(ctx, tc.Wait(true)); err != nil {
+		return fmt.Errorf("compose up failed: %w", err)
+	}
+	return nil
+}
+
+func (c *composeHandler) Stop(ctx context.Context) error {
+	if err := c.c.Down(ctx); err != nil {
+		return fmt.Errorf("compose down failed: %w", err)
+	}
+	return nil
+}
+
+func (c *composeHandler) Name() string {
+	return "compose"
+}
@@ -0,0 +1,201 @@
+package integrationtest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OptCoverOutput sets the path the merged coverage profile is written to
+// once the test binary has exited. Defaults to "coverage.out" inside the
+// directory set by OptCoverDir. Requires OptCoverDir to be set.
+func OptCoverOutput(path string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.coverOutput = path
+		return nil
+	}
+}
+
+// OptCoverHTML renders the merged coverage profile as an HTML report at
+// path via `go tool cover -html`. Requires OptCoverDir to be set.
+func OptCoverHTML(path string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.coverHTML = path
+		return nil
+	}
+}
+
+// OptUnitCoverProfile unions an existing coverage profile, e.g. one
+// produced by `go test -coverprofile`, into the merged integration
+// coverage profile so the final report covers both.
+func OptUnitCoverProfile(path string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.unitCoverProfile = path
+		return nil
+	}
+}
+
+// OptCoverThreshold fails Stop with an error if the merged profile's total
+// statement coverage is below pct, e.g. 80.0 for 80%.
+func OptCoverThreshold(pct float64) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.coverThreshold = pct
+		return nil
+	}
+}
+
+// mergeCoverage merges the raw GOCOVERDIR counter data collected from the
+// test binary into a single text coverage profile, optionally unions it
+// with a unit test profile, renders an HTML report and enforces the
+// configured coverage threshold. It is a no-op if OptCoverDir was never
+// set.
+func (itr *IntegrationTestRunner) mergeCoverage() error {
+	coverDir := itr.binHandler.coverDir
+	if coverDir == "" {
+		return nil
+	}
+
+	out := itr.coverOutput
+	if out == "" {
+		out = filepath.Join(coverDir, "coverage.out")
+	}
+
+	mergedDir, err := os.MkdirTemp("", "integrationtest-covdata")
+	if err != nil {
+		return fmt.Errorf("creating merged covdata dir failed: %w", err)
+	}
+	defer os.RemoveAll(mergedDir)
+
+	if err := runGoTool(itr.base, "tool", "covdata", "merge", "-i="+coverDir, "-o="+mergedDir); err != nil {
+		return fmt.Errorf("merging covdata failed: %w", err)
+	}
+
+	if err := runGoTool(itr.base, "tool", "covdata", "textfmt", "-i="+mergedDir, "-o="+out); err != nil {
+		return fmt.Errorf("converting covdata to text profile failed: %w", err)
+	}
+
+	if itr.unitCoverProfile != "" {
+		if err := unionCoverProfiles(out, itr.unitCoverProfile, out); err != nil {
+			return fmt.Errorf("unioning unit coverage profile failed: %w", err)
+		}
+	}
+
+	if itr.coverHTML != "" {
+		if err := runGoTool(itr.base, "tool", "cover", "-html="+out, "-o="+itr.coverHTML); err != nil {
+			return fmt.Errorf("rendering HTML coverage report failed: %w", err)
+		}
+	}
+
+	if itr.coverThreshold > 0 {
+		pct, err := totalCoverage(out)
+		if err != nil {
+			return fmt.Errorf("computing total coverage failed: %w", err)
+		}
+
+		if pct < itr.coverThreshold {
+			return fmt.Errorf("total coverage %.1f%% is below threshold %.1f%%", pct, itr.coverThreshold)
+		}
+	}
+
+	return nil
+}
+
+// unionCoverProfiles concatenates the statement coverage lines of a and b
+// into out, keeping a single "mode:" header. This assumes a and b do not
+// cover overlapping statements (true for the common case of unit tests and
+// integration tests exercising different code paths); overlapping lines
+// are kept as separate entries rather than summed, which `go tool cover`
+// still renders correctly for -html and -func.
+func unionCoverProfiles(a, b, out string) error {
+	mode, lines, err := readCoverProfile(a)
+	if err != nil {
+		return fmt.Errorf("reading %s failed: %w", a, err)
+	}
+
+	_, bLines, err := readCoverProfile(b)
+	if err != nil {
+		return fmt.Errorf("reading %s failed: %w", b, err)
+	}
+	lines = append(lines, bLines...)
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("creating %s failed: %w", out, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, "mode: "+mode); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCoverProfile(path string) (mode string, lines []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode: ") {
+			mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return mode, lines, scanner.Err()
+}
+
+// totalCoverage runs `go tool cover -func` against profile and parses the
+// "total:" line it prints, returning the percentage as a float.
+func totalCoverage(profile string) (float64, error) {
+	cmd := exec.Command("go", "tool", "cover", "-func="+profile) //nolint:gosec
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("go tool cover -func failed: %w", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.HasPrefix(line, "total:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		pctField := fields[len(fields)-1]
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(pctField, "%"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing total coverage %q failed: %w", pctField, err)
+		}
+		return pct, nil
+	}
+
+	return 0, fmt.Errorf("no total coverage line found in output of %s", profile)
+}
+
+// runGoTool runs `go` with args in dir, forwarding its output to the
+// process' own stdout/stderr.
+func runGoTool(dir string, args ...string) error {
+	cmd := exec.Command("go", args...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package integrationtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCoverProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantMode  string
+		wantLines []string
+	}{
+		{
+			name:      "single statement",
+			content:   "mode: set\nexample.com/pkg/foo.go:10.2,12.3 1 1\n",
+			wantMode:  "set",
+			wantLines: []string{"example.com/pkg/foo.go:10.2,12.3 1 1"},
+		},
+		{
+			name:      "multiple statements with a blank line",
+			content:   "mode: atomic\nexample.com/pkg/foo.go:10.2,12.3 1 1\n\nexample.com/pkg/bar.go:5.1,6.3 2 0\n",
+			wantMode:  "atomic",
+			wantLines: []string{"example.com/pkg/foo.go:10.2,12.3 1 1", "example.com/pkg/bar.go:5.1,6.3 2 0"},
+		},
+		{
+			name:      "mode line only",
+			content:   "mode: set\n",
+			wantMode:  "set",
+			wantLines: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cover.out")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			mode, lines, err := readCoverProfile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMode, mode)
+			assert.Equal(t, tt.wantLines, lines)
+		})
+	}
+}
+
+func TestReadCoverProfileMissingFile(t *testing.T) {
+	_, _, err := readCoverProfile(filepath.Join(t.TempDir(), "missing.out"))
+	assert.Error(t, err)
+}
+
+func TestUnionCoverProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	a := filepath.Join(dir, "a.out")
+	require.NoError(t, os.WriteFile(a, []byte("mode: set\nexample.com/pkg/foo.go:10.2,12.3 1 1\n"), 0o644))
+
+	b := filepath.Join(dir, "b.out")
+	require.NoError(t, os.WriteFile(b, []byte("mode: set\nexample.com/pkg/bar.go:5.1,6.3 2 0\n"), 0o644))
+
+	out := filepath.Join(dir, "merged.out")
+	require.NoError(t, unionCoverProfiles(a, b, out))
+
+	mode, lines, err := readCoverProfile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "set", mode)
+	assert.Equal(t, []string{
+		"example.com/pkg/foo.go:10.2,12.3 1 1",
+		"example.com/pkg/bar.go:5.1,6.3 2 0",
+	}, lines)
+}
+
+func TestUnionCoverProfilesMissingInput(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "merged.out")
+
+	err := unionCoverProfiles(filepath.Join(dir, "missing-a.out"), filepath.Join(dir, "missing-b.out"), out)
+	assert.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package envtest
+
+import "net"
+
+// freePort binds an ephemeral TCP port on 127.0.0.1, closes it
+// immediately and returns the address it was bound to. Mirrors
+// controller-runtime's testing/addr package; see integrationtest's own
+// reservePorts for the same pattern applied to the wrapped test binary.
+func freePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	addr := l.Addr().String()
+	return addr, l.Close()
+}
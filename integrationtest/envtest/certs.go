@@ -0,0 +1,138 @@
+package envtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// certValidity is generous enough to outlive any single test run without
+// bothering with rotation; these keys never leave the envtest work dir.
+const certValidity = 24 * time.Hour
+
+// certPair is a PEM-encoded certificate and its private key, written to
+// disk for consumption by kube-apiserver and the admin kubeconfig.
+type certPair struct {
+	certPath string
+	keyPath  string
+}
+
+// generateCA creates a tiny self-signed CA used as the apiserver's
+// client-ca-file and to sign the admin client certificate. A trimmed-down version
+// of controller-runtime's testing/certs package: no intermediates, no
+// rotation.
+func generateCA(dir string) (*x509.Certificate, *rsa.PrivateKey, certPair, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, certPair{}, fmt.Errorf("generating CA key failed: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "integrationtest-envtest-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(certValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, certPair{}, fmt.Errorf("signing CA cert failed: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, certPair{}, fmt.Errorf("parsing CA cert failed: %w", err)
+	}
+
+	pair, err := writeCertPair(dir, "ca", der, key)
+	if err != nil {
+		return nil, nil, certPair{}, err
+	}
+
+	return cert, key, pair, nil
+}
+
+// generateClientCert mints a client certificate signed by ca. commonName
+// and organization become the Kubernetes authenticator's username and
+// group; "system:masters" grants cluster-admin.
+func generateClientCert(dir, name, commonName, organization string, ca *x509.Certificate, caKey *rsa.PrivateKey) (certPair, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return certPair{}, fmt.Errorf("generating %s key failed: %w", name, err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{organization}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return certPair{}, fmt.Errorf("signing %s cert failed: %w", name, err)
+	}
+
+	return writeCertPair(dir, name, der, key)
+}
+
+// generateServiceAccountKeyPair creates the RSA key pair kube-apiserver
+// uses to sign and verify service account tokens.
+func generateServiceAccountKeyPair(dir string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", fmt.Errorf("generating service account key failed: %w", err)
+	}
+
+	path := filepath.Join(dir, "sa.key")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("creating %s failed: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := pem.Encode(f, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		return "", fmt.Errorf("writing %s failed: %w", path, err)
+	}
+
+	return path, nil
+}
+
+func writeCertPair(dir, name string, der []byte, key *rsa.PrivateKey) (certPair, error) {
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+
+	certOut, err := os.Create(certPath)
+	if err != nil {
+		return certPair{}, fmt.Errorf("creating %s failed: %w", certPath, err)
+	}
+	defer certOut.Close()
+
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		return certPair{}, fmt.Errorf("writing %s failed: %w", certPath, err)
+	}
+
+	keyOut, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return certPair{}, fmt.Errorf("creating %s failed: %w", keyPath, err)
+	}
+	defer keyOut.Close()
+
+	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		return certPair{}, fmt.Errorf("writing %s failed: %w", keyPath, err)
+	}
+
+	return certPair{certPath: certPath, keyPath: keyPath}, nil
+}
@@ -0,0 +1,237 @@
+package envtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/client-go/rest"
+)
+
+// startTimeout bounds how long etcd and kube-apiserver each get to start
+// accepting connections.
+const startTimeout = 20 * time.Second
+
+// Handler brings up a local kube-apiserver and etcd so Kubernetes
+// controllers can be integration tested without Docker. Register it with
+// integrationtest.OptPreHandler:
+//
+//	env := envtest.New("1.29.0")
+//	itr, err := integrationtest.NewIntegrationTestRunner(
+//		integrationtest.OptPreHandler(env),
+//		integrationtest.OptTestMain(m),
+//	)
+//
+// Handler also implements integrationtest.EnvProvider, so the test binary
+// gets KUBECONFIG set automatically; call RESTConfig from the test
+// process itself to talk to the cluster directly.
+type Handler struct {
+	version string
+
+	dir string
+
+	etcd      *process
+	apiserver *process
+
+	restConfig     *rest.Config
+	kubeconfigPath string
+}
+
+// New creates a Handler for the given Kubernetes version, e.g. "1.29.0".
+// The matching kube-apiserver and etcd binaries must already be present,
+// e.g. via `setup-envtest use <version>`.
+func New(version string) *Handler {
+	return &Handler{version: version}
+}
+
+// Name implements integrationtest.Handler.
+func (h *Handler) Name() string {
+	return "envtest"
+}
+
+// Start resolves the kube-apiserver/etcd binaries, starts etcd, then
+// kube-apiserver pointed at it, and writes an admin kubeconfig.
+func (h *Handler) Start(ctx context.Context) error {
+	apiserverBin, etcdBin, err := resolve(h.version)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "integrationtest-envtest")
+	if err != nil {
+		return fmt.Errorf("creating envtest work dir failed: %w", err)
+	}
+	h.dir = dir
+
+	if err := h.startEtcd(ctx, etcdBin); err != nil {
+		return fmt.Errorf("starting etcd failed: %w", err)
+	}
+
+	if err := h.startAPIServer(ctx, apiserverBin); err != nil {
+		return fmt.Errorf("starting kube-apiserver failed: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Handler) startEtcd(ctx context.Context, etcdBin string) error {
+	clientAddr, err := freePort()
+	if err != nil {
+		return fmt.Errorf("reserving client port failed: %w", err)
+	}
+
+	peerAddr, err := freePort()
+	if err != nil {
+		return fmt.Errorf("reserving peer port failed: %w", err)
+	}
+
+	h.etcd = &process{
+		name: "etcd",
+		bin:  etcdBin,
+		args: []string{
+			"--data-dir=" + filepath.Join(h.dir, "etcd"),
+			"--listen-client-urls=http://" + clientAddr,
+			"--advertise-client-urls=http://" + clientAddr,
+			"--listen-peer-urls=http://" + peerAddr,
+			"--initial-cluster=default=http://" + peerAddr,
+			"--initial-advertise-peer-urls=http://" + peerAddr,
+		},
+		healthAddr: clientAddr,
+	}
+
+	return h.etcd.start(ctx, startTimeout)
+}
+
+func (h *Handler) startAPIServer(ctx context.Context, apiserverBin string) error {
+	ca, caKey, caPair, err := generateCA(h.dir)
+	if err != nil {
+		return err
+	}
+
+	admin, err := generateClientCert(h.dir, "admin", "envtest-admin", "system:masters", ca, caKey)
+	if err != nil {
+		return err
+	}
+
+	saKeyPath, err := generateServiceAccountKeyPair(h.dir)
+	if err != nil {
+		return err
+	}
+
+	apiserverAddr, err := freePort()
+	if err != nil {
+		return fmt.Errorf("reserving port failed: %w", err)
+	}
+
+	_, apiserverPort, err := net.SplitHostPort(apiserverAddr)
+	if err != nil {
+		return fmt.Errorf("parsing %q failed: %w", apiserverAddr, err)
+	}
+
+	h.apiserver = &process{
+		name: "kube-apiserver",
+		bin:  apiserverBin,
+		args: []string{
+			"--etcd-servers=http://" + h.etcd.healthAddr,
+			"--secure-port=" + apiserverPort,
+			"--cert-dir=" + filepath.Join(h.dir, "apiserver-certs"),
+			"--client-ca-file=" + caPair.certPath,
+			"--service-account-key-file=" + saKeyPath,
+			"--service-account-signing-key-file=" + saKeyPath,
+			"--service-account-issuer=https://envtest.local",
+			"--service-cluster-ip-range=10.0.0.0/24",
+			"--authorization-mode=RBAC",
+		},
+		healthAddr: apiserverAddr,
+	}
+
+	if err := h.apiserver.start(ctx, startTimeout); err != nil {
+		return err
+	}
+
+	h.restConfig = &rest.Config{
+		Host: "https://" + apiserverAddr,
+		TLSClientConfig: rest.TLSClientConfig{
+			CertFile: admin.certPath,
+			KeyFile:  admin.keyPath,
+			// kube-apiserver self-signs its serving cert into cert-dir;
+			// envtest has no use for verifying it since both ends run on
+			// loopback for the lifetime of a single test.
+			Insecure: true,
+		},
+	}
+
+	return h.writeKubeconfig(apiserverAddr, admin)
+}
+
+func (h *Handler) writeKubeconfig(apiserverAddr string, admin certPair) error {
+	const tmpl = `apiVersion: v1
+kind: Config
+clusters:
+- name: envtest
+  cluster:
+    server: https://%s
+    insecure-skip-tls-verify: true
+users:
+- name: admin
+  user:
+    client-certificate: %s
+    client-key: %s
+contexts:
+- name: envtest
+  context:
+    cluster: envtest
+    user: admin
+current-context: envtest
+`
+	path := filepath.Join(h.dir, "kubeconfig")
+	content := fmt.Sprintf(tmpl, apiserverAddr, admin.certPath, admin.keyPath)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("writing kubeconfig failed: %w", err)
+	}
+
+	h.kubeconfigPath = path
+	return nil
+}
+
+// Stop terminates kube-apiserver and etcd and removes their working
+// directory.
+func (h *Handler) Stop(_ context.Context) error {
+	var result *multierror.Error
+
+	if h.apiserver != nil {
+		if err := h.apiserver.stop(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	if h.etcd != nil {
+		if err := h.etcd.stop(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	if h.dir != "" {
+		if err := os.RemoveAll(h.dir); err != nil {
+			result = multierror.Append(result, fmt.Errorf("removing envtest work dir failed: %w", err))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// RESTConfig returns a *rest.Config for talking to the running
+// kube-apiserver. Only valid after Start has returned successfully.
+func (h *Handler) RESTConfig() *rest.Config {
+	return h.restConfig
+}
+
+// Env implements integrationtest.EnvProvider, exporting KUBECONFIG so the
+// test binary can talk to the running cluster without extra wiring.
+func (h *Handler) Env() []string {
+	return []string{"KUBECONFIG=" + h.kubeconfigPath}
+}
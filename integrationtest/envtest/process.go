@@ -0,0 +1,101 @@
+package envtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// healthPollInterval is how often process polls healthAddr while waiting
+// for a child to become ready.
+const healthPollInterval = 100 * time.Millisecond
+
+// process starts and supervises a single external binary (etcd or
+// kube-apiserver), capturing its stdout/stderr so failures are easy to
+// diagnose and polling a TCP address until the binary accepts
+// connections. Mirrors controller-runtime's testing/process package.
+type process struct {
+	name string
+	bin  string
+	args []string
+
+	// healthAddr is polled with a plain TCP dial; both etcd and
+	// kube-apiserver accept connections on their client/secure port well
+	// before they're done initializing, but refusing to accept at all is
+	// a reliable "not up yet" signal and needs no protocol-specific
+	// health check.
+	healthAddr string
+
+	cmd    *exec.Cmd
+	stdout syncBuffer
+	stderr syncBuffer
+}
+
+// syncBuffer guards a bytes.Buffer with a mutex so it can be read (e.g. by
+// waitHealthy's timeout error) while os/exec's output-copying goroutine may
+// still be writing to it, i.e. before stop() has reaped the child.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// start launches the binary and blocks until healthAddr accepts
+// connections or startTimeout elapses.
+func (p *process) start(ctx context.Context, startTimeout time.Duration) error {
+	p.cmd = exec.CommandContext(ctx, p.bin, p.args...) //nolint:gosec
+	p.cmd.Stdout = &p.stdout
+	p.cmd.Stderr = &p.stderr
+
+	if err := p.cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s failed: %w", p.name, err)
+	}
+
+	if err := p.waitHealthy(startTimeout); err != nil {
+		_ = p.stop()
+		return err
+	}
+	return nil
+}
+
+func (p *process) waitHealthy(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", p.healthAddr, healthPollInterval)
+		if err == nil {
+			return conn.Close()
+		}
+		time.Sleep(healthPollInterval)
+	}
+
+	return fmt.Errorf("%s did not become ready on %s within %s, stderr:\n%s", p.name, p.healthAddr, timeout, p.stderr.String())
+}
+
+// stop kills the child and waits for it to exit.
+func (p *process) stop() error {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return nil
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("killing %s failed: %w", p.name, err)
+	}
+
+	_ = p.cmd.Wait()
+	return nil
+}
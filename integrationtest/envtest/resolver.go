@@ -0,0 +1,52 @@
+// Package envtest provides an integrationtest.Handler that brings up a
+// local kube-apiserver and etcd, so Kubernetes controllers can be
+// integration tested without Docker. Binaries are located the same way
+// the setup-envtest CLI and controller-runtime's own envtest package do.
+package envtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// assetsEnvVar is the well-known override used by setup-envtest and
+// controller-runtime's envtest package to point at a directory already
+// containing kube-apiserver, etcd and kubectl.
+const assetsEnvVar = "KUBEBUILDER_ASSETS"
+
+// resolve locates the kube-apiserver and etcd binaries for version (e.g.
+// "1.29.0"). It honors KUBEBUILDER_ASSETS first, falling back to the
+// setup-envtest on-disk store under the user's home directory.
+func resolve(version string) (apiserverBin, etcdBin string, err error) {
+	dirs := []string{defaultStoreDir(version)}
+	if d := os.Getenv(assetsEnvVar); d != "" {
+		dirs = append([]string{d}, dirs...)
+	}
+
+	for _, dir := range dirs {
+		apiserverBin = filepath.Join(dir, "kube-apiserver")
+		etcdBin = filepath.Join(dir, "etcd")
+		if isExecutable(apiserverBin) && isExecutable(etcdBin) {
+			return apiserverBin, etcdBin, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("could not find kube-apiserver/etcd for version %q in %v; run `setup-envtest use %s` first, or set %s", version, dirs, version, assetsEnvVar)
+}
+
+// defaultStoreDir is where setup-envtest stores downloaded binaries,
+// keyed by Kubernetes version, GOOS and GOARCH.
+func defaultStoreDir(version string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".local", "share", "kubebuilder-envtest", "k8s", fmt.Sprintf("%s-%s-%s", version, runtime.GOOS, runtime.GOARCH))
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
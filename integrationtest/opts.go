@@ -1,15 +1,25 @@
 package integrationtest
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"path"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/testcontainers/testcontainers-go"
 	tc "github.com/testcontainers/testcontainers-go/modules/compose"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // Opt is option type for IntegrationTestRunner.
@@ -103,10 +113,25 @@ func OptCoverDir(coverDir string) Opt {
 //	}
 //
 // Before using this pattern be sure to read how TestMain should be used!
+//
+// Combine with OptJSONOutput and/or OptJUnitOutput to additionally get
+// go-test-json-compatible events and a JUnit report out of m.Run(); verbose
+// output is forced in-process, so there's no need to pass -test.v yourself.
 func OptTestMain(m *testing.M) Opt {
 	return func(itr *IntegrationTestRunner) error {
 		itr.testRunner = func() error {
-			if code := m.Run(); code != 0 {
+			var code int
+			if itr.jsonOutput == nil && itr.junitOutput == "" {
+				code = m.Run()
+			} else {
+				var err error
+				code, err = itr.runWithJSONCapture(m)
+				if err != nil {
+					return err
+				}
+			}
+
+			if code != 0 {
 				return errors.New("tests have failed")
 			}
 			return nil
@@ -160,27 +185,233 @@ func OptCompose(composeFile string, opts ...ComposeOpt) Opt {
 		}
 
 		itr.preHandlers = append(itr.preHandlers, c)
+		itr.compose = c
+		return nil
+	}
+}
+
+// OptPreHandler registers h to be started before the test binary runs and
+// stopped, in reverse order of registration, after the test run finishes.
+// OptCompose registers its compose stack as a pre handler this way.
+func OptPreHandler(h Handler) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.preHandlers = append(itr.preHandlers, h)
+		return nil
+	}
+}
+
+// OptPostHandler registers h to be stopped, in reverse order of
+// registration, after the test run finishes and the test binary and pre
+// handlers have been stopped.
+func OptPostHandler(h Handler) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.postHandlers = append(itr.postHandlers, h)
 		return nil
 	}
 }
 
 // OptWaitHTTPReady expects 200 OK from given url before tests can be started.
+// Multiple OptWait* options can be combined; all of them must pass, in
+// parallel, before the test runner is started.
 func OptWaitHTTPReady(url string, timeout time.Duration) Opt {
 	return func(itr *IntegrationTestRunner) error {
-		itr.ready = func() error {
-			started := time.Now()
-			for !isReady(url) {
-				if time.Since(started) > timeout {
+		if itr.pprofBaseURL == "" {
+			base, err := baseURL(url)
+			if err != nil {
+				return fmt.Errorf("parsing %q failed: %w", url, err)
+			}
+			itr.pprofBaseURL = base
+		}
+
+		addReady(itr, "http:"+url, timeout, func(ctx context.Context) error {
+			for {
+				if isReady(url) {
+					return nil
+				}
+				select {
+				case <-ctx.Done():
 					return fmt.Errorf("readiness deadline %s exceeded", timeout)
+				case <-time.After(time.Millisecond * 100):
 				}
-				time.Sleep(time.Millisecond * 100)
 			}
-			return nil
+		})
+		return nil
+	}
+}
+
+// OptWaitTCPReady expects a TCP connection to addr to succeed before tests
+// can be started. Useful for dependencies without an HTTP health endpoint,
+// e.g. Postgres or Kafka brought up via OptCompose.
+func OptWaitTCPReady(addr string, timeout time.Duration) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		addReady(itr, "tcp:"+addr, timeout, func(ctx context.Context) error {
+			d := net.Dialer{}
+			for {
+				conn, err := d.DialContext(ctx, "tcp", addr)
+				if err == nil {
+					return conn.Close()
+				}
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("readiness deadline %s exceeded: %w", timeout, err)
+				case <-time.After(time.Millisecond * 100):
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// OptWaitGRPCReady expects the standard grpc.health.v1.Health/Check RPC
+// against the given service on target to report SERVING before tests can be
+// started. An empty service checks the server's overall health.
+func OptWaitGRPCReady(target, service string, timeout time.Duration) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		addReady(itr, "grpc:"+target, timeout, func(ctx context.Context) error {
+			conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				return fmt.Errorf("dialing %s failed: %w", target, err)
+			}
+			defer conn.Close()
+
+			client := grpc_health_v1.NewHealthClient(conn)
+			for {
+				resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+				if err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_SERVING {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("readiness deadline %s exceeded", timeout)
+				case <-time.After(time.Millisecond * 100):
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// OptWaitLogLine expects the stdout/stderr of the given compose service to
+// emit a line matching regex before tests can be started. Requires
+// OptCompose to be set first.
+func OptWaitLogLine(service, regex string, timeout time.Duration) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return fmt.Errorf("compiling log line regex %q failed: %w", regex, err)
 		}
+
+		addReady(itr, "logline:"+service, timeout, func(ctx context.Context) error {
+			if itr.compose == nil {
+				return errors.New("OptWaitLogLine requires OptCompose to be set first")
+			}
+
+			container, err := itr.compose.c.ServiceContainer(ctx, service)
+			if err != nil {
+				return fmt.Errorf("getting container for service %q failed: %w", service, err)
+			}
+
+			// container.Logs returns a snapshot of the output collected so
+			// far, not a live stream, so it has to be re-opened on every
+			// poll until the line we want shows up or ctx's deadline hits.
+			for {
+				if matched, err := logsMatch(ctx, container, re); err != nil {
+					return fmt.Errorf("reading logs for service %q failed: %w", service, err)
+				} else if matched {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("log line matching %q not seen for service %q within %s", regex, service, timeout)
+				case <-time.After(time.Millisecond * 100):
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// OptWaitFunc runs fn as a readiness check, for waiting on conditions not
+// covered by the other OptWait* options.
+func OptWaitFunc(fn func(ctx context.Context) error, timeout time.Duration) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		addReady(itr, "func", timeout, fn)
+		return nil
+	}
+}
+
+// OptRunTimeout bounds how long the test binary is allowed to run. Once
+// exceeded, it is sent SIGTERM and, if it hasn't exited within a short
+// grace period, SIGKILL. The resulting RunResult.Killed is set to true.
+func OptRunTimeout(d time.Duration) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.binHandler.runTimeout = d
+		return nil
+	}
+}
+
+// OptMemoryLimitMB caps the test binary's address space (RLIMIT_AS) at mb
+// megabytes. Unix only; ignored on other platforms.
+func OptMemoryLimitMB(mb int) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.binHandler.memoryLimitMB = mb
+		return nil
+	}
+}
+
+// OptCPULimit caps the test binary's CPU time (RLIMIT_CPU) at sec seconds.
+// Unix only; ignored on other platforms.
+func OptCPULimit(sec int) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.binHandler.cpuLimitSec = sec
 		return nil
 	}
 }
 
+// OptStdinFrom connects r to the test binary's stdin.
+func OptStdinFrom(r io.Reader) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.binHandler.stdin = r
+		return nil
+	}
+}
+
+// OptCgroup places the test binary in a transient cgroup v2 child named
+// name under /sys/fs/cgroup, enforcing OptMemoryLimitMB there as the
+// cgroup's memory.max so memory pressure from the system under test can't
+// OOM the whole test host. Linux only.
+func OptCgroup(name string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.binHandler.cgroupName = name
+		return nil
+	}
+}
+
+func addReady(itr *IntegrationTestRunner, name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	itr.ready = append(itr.ready, readinessCheck{name: name, timeout: timeout, fn: fn})
+}
+
+// logsMatch fetches container's current log snapshot and reports whether
+// any line matches re.
+func logsMatch(ctx context.Context, container *testcontainers.DockerContainer, re *regexp.Regexp) (bool, error) {
+	logs, err := container.Logs(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		if re.MatchString(scanner.Text()) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
 func isReady(url string) bool {
 	r, err := http.Get(url) //nolint:gosec
 	if err != nil {
@@ -189,4 +420,13 @@ func isReady(url string) bool {
 	defer r.Body.Close()
 
 	return r.StatusCode == http.StatusOK
-}
\ No newline at end of file
+}
+
+// baseURL strips the path/query off rawURL, leaving just "scheme://host".
+func baseURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
@@ -0,0 +1,87 @@
+package integrationtest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// reservePortRetries bounds the number of attempts reserveOne makes to bind
+// an ephemeral port before giving up.
+const reservePortRetries = 3
+
+// OptReservePort reserves an ephemeral TCP port for name before the test
+// binary is started. The reserved host:port pair is exported to the child
+// binary as an IT_PORT_<NAME> env var (e.g. "api" becomes IT_PORT_API) and
+// made available to the wrapped test run via Port.
+func OptReservePort(name string) Opt {
+	return OptReservePorts(name)
+}
+
+// OptReservePorts is the variadic form of OptReservePort.
+func OptReservePorts(names ...string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.portNames = append(itr.portNames, names...)
+		return nil
+	}
+}
+
+// Port returns the host:port pair reserved for name via OptReservePort or
+// OptReservePorts. It returns an empty string if name was never reserved.
+func (itr *IntegrationTestRunner) Port(name string) string {
+	return itr.ports[name]
+}
+
+// reservePorts binds an ephemeral TCP listener for every registered port
+// name, closes it immediately and records the resulting host:port pair,
+// both in itr.ports and as an IT_PORT_<NAME> entry in the test binary's
+// runEnv.
+//
+// There is an inherent TOCTOU race between closing the listener and the
+// child process binding the same address: another process on the host
+// could claim the port in between. reserveOne retries only when binding or
+// closing a listener fails; nothing guards the window after the close, so
+// callers running many parallel suites on a loaded host should still
+// expect the rare flake.
+func (itr *IntegrationTestRunner) reservePorts() error {
+	if len(itr.portNames) == 0 {
+		return nil
+	}
+
+	itr.ports = make(map[string]string, len(itr.portNames))
+
+	for _, name := range itr.portNames {
+		addr, err := reserveOne()
+		if err != nil {
+			return fmt.Errorf("reserving port %q failed: %w", name, err)
+		}
+
+		itr.ports[name] = addr
+		itr.binHandler.runEnv = append(itr.binHandler.runEnv, fmt.Sprintf("IT_PORT_%s=%s", strings.ToUpper(name), addr))
+	}
+
+	return nil
+}
+
+// reserveOne binds an ephemeral TCP listener, closes it immediately and
+// returns the address it was bound to, retrying a few times on failure.
+func reserveOne() (string, error) {
+	var lastErr error
+	for i := 0; i < reservePortRetries; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		addr := l.Addr().String()
+		if err := l.Close(); err != nil {
+			lastErr = err
+			continue
+		}
+
+		return addr, nil
+	}
+
+	return "", fmt.Errorf("after %d attempts: %w", reservePortRetries, lastErr)
+}
@@ -0,0 +1,56 @@
+package integrationtest
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveOne(t *testing.T) {
+	addr, err := reserveOne()
+	require.NoError(t, err)
+	require.NotEmpty(t, addr)
+
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	assert.NotEmpty(t, port)
+
+	// the listener was closed again, so the address must be free to bind
+	l, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	assert.NoError(t, l.Close())
+}
+
+func TestReservePorts(t *testing.T) {
+	tests := []struct {
+		name      string
+		portNames []string
+	}{
+		{name: "no ports registered", portNames: nil},
+		{name: "single port", portNames: []string{"api"}},
+		{name: "multiple ports", portNames: []string{"api", "metrics"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itr := &IntegrationTestRunner{binHandler: &binHandler{}, portNames: tt.portNames}
+
+			require.NoError(t, itr.reservePorts())
+			assert.Len(t, itr.ports, len(tt.portNames))
+			assert.Len(t, itr.binHandler.runEnv, len(tt.portNames))
+
+			for _, name := range tt.portNames {
+				addr, ok := itr.ports[name]
+				assert.True(t, ok)
+				assert.NotEmpty(t, addr)
+
+				want := "IT_PORT_" + strings.ToUpper(name) + "=" + addr
+				assert.Contains(t, itr.binHandler.runEnv, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package integrationtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/elisasre/go-common/service/module/httpserver/pprof"
+)
+
+// OptPprofCapture snapshots the SUT's net/http/pprof profiles into dir
+// right after Run finishes, before Stop tears down compose and the other
+// pre handlers. Requires OptWaitHTTPReady to be set, whose URL is reused
+// as the SUT's pprof base URL. If onFailureOnly is true, the snapshot is
+// only taken when Run returned an error; otherwise it is always taken.
+// This turns a flaky integration test failure into a heap/goroutine/CPU
+// snapshot instead of just a failing log line.
+func OptPprofCapture(dir string, onFailureOnly bool) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.pprofDir = dir
+		itr.pprofOnFailureOnly = onFailureOnly
+		return nil
+	}
+}
+
+// capturePprof is called by InitAndRun right after Run, with runErr being
+// whatever Run returned.
+func (itr *IntegrationTestRunner) capturePprof(runErr error) error {
+	if itr.pprofDir == "" {
+		return nil
+	}
+	if itr.pprofOnFailureOnly && runErr == nil {
+		return nil
+	}
+	if itr.pprofBaseURL == "" {
+		return errors.New("OptPprofCapture requires OptWaitHTTPReady to be set first")
+	}
+
+	if _, err := pprof.Capture(context.Background(), itr.pprofBaseURL, pprof.CaptureOpts{Dir: itr.pprofDir}); err != nil {
+		return fmt.Errorf("capturing pprof profiles failed: %w", err)
+	}
+	return nil
+}
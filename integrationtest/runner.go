@@ -0,0 +1,233 @@
+// Package integrationtest provides a runner that builds a Go binary, brings
+// up its dependencies (e.g. a docker compose stack), waits for the system
+// under test to become ready and then hands control to the wrapped test
+// suite.
+package integrationtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// IntegrationTestRunner builds and runs a test binary alongside optional
+// pre/post handlers and readiness checks before handing control to the
+// wrapped test run.
+type IntegrationTestRunner struct {
+	base string
+
+	binHandler *binHandler
+
+	preHandlers  []Handler
+	postHandlers []Handler
+
+	// compose is set by OptCompose and reused by readiness checks that need
+	// access to the compose stack, e.g. OptWaitLogLine.
+	compose *composeHandler
+
+	ready []readinessCheck
+
+	// portNames are the names registered via OptReservePort/OptReservePorts.
+	// ports holds the reserved host:port pairs once Init has run.
+	portNames []string
+	ports     map[string]string
+
+	// coverage pipeline configuration, set by OptCoverOutput, OptCoverHTML,
+	// OptUnitCoverProfile and OptCoverThreshold. Only used if OptCoverDir
+	// is also set.
+	coverOutput      string
+	coverHTML        string
+	unitCoverProfile string
+	coverThreshold   float64
+
+	// jsonOutput and junitOutput are set by OptJSONOutput/OptJUnitOutput
+	// and consumed by OptTestMain's testRunner.
+	jsonOutput  io.Writer
+	junitOutput string
+
+	// pprofBaseURL is learned from the first OptWaitHTTPReady call and
+	// reused by OptPprofCapture as the SUT's net/http/pprof base URL.
+	pprofBaseURL       string
+	pprofDir           string
+	pprofOnFailureOnly bool
+
+	testRunner func() error
+}
+
+// Handler is stopped after the test run finishes, in reverse order of
+// registration. Handlers registered with OptPreHandler are also started
+// before the test binary is run; handlers registered with OptPostHandler
+// are only stopped, never started. OptCompose registers a Handler of its
+// own as a pre handler.
+type Handler interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Name() string
+}
+
+// EnvProvider is implemented by Handlers that need to export environment
+// variables to the test binary once started, e.g. envtest.Handler
+// exporting KUBECONFIG.
+type EnvProvider interface {
+	Env() []string
+}
+
+// readinessCheck is a single named readiness gate with its own timeout.
+type readinessCheck struct {
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+}
+
+// NewIntegrationTestRunner creates an IntegrationTestRunner and applies the
+// given options in order.
+func NewIntegrationTestRunner(opts ...Opt) (*IntegrationTestRunner, error) {
+	itr := &IntegrationTestRunner{
+		binHandler: &binHandler{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(itr); err != nil {
+			return nil, fmt.Errorf("applying option failed: %w", err)
+		}
+	}
+
+	return itr, nil
+}
+
+// Init builds the test binary, starts the registered pre handlers, runs the
+// binary and waits until all registered readiness checks pass.
+func (itr *IntegrationTestRunner) Init() error {
+	ctx := context.Background()
+
+	if err := itr.binHandler.build(); err != nil {
+		return fmt.Errorf("building test binary failed: %w", err)
+	}
+
+	if err := itr.reservePorts(); err != nil {
+		return fmt.Errorf("reserving ports failed: %w", err)
+	}
+
+	for _, h := range itr.preHandlers {
+		if err := h.Start(ctx); err != nil {
+			return fmt.Errorf("starting %s failed: %w", h.Name(), err)
+		}
+
+		if p, ok := h.(EnvProvider); ok {
+			itr.binHandler.runEnv = append(itr.binHandler.runEnv, p.Env()...)
+		}
+	}
+
+	if err := itr.binHandler.run(); err != nil {
+		return fmt.Errorf("running test binary failed: %w", err)
+	}
+
+	return itr.waitReady(ctx)
+}
+
+// waitReady runs all registered readiness checks in parallel, each bounded
+// by its own independent timeout, and waits for every one of them to pass,
+// fail or time out, returning the combined errors of those that did not
+// pass.
+func (itr *IntegrationTestRunner) waitReady(ctx context.Context) error {
+	if len(itr.ready) == 0 {
+		return nil
+	}
+
+	wg := &multierror.Group{}
+	for _, c := range itr.ready {
+		c := c
+		wg.Go(func() error {
+			cctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			if err := c.fn(cctx); err != nil {
+				return fmt.Errorf("readiness check %q failed: %w", c.name, err)
+			}
+			return nil
+		})
+	}
+
+	return wg.Wait().ErrorOrNil()
+}
+
+// Run executes the wrapped test runner set by OptTestMain or OptTestFunc.
+func (itr *IntegrationTestRunner) Run() error {
+	if itr.testRunner == nil {
+		return errors.New("no test runner configured, use OptTestMain or OptTestFunc")
+	}
+	return itr.testRunner()
+}
+
+// Stop stops the test binary and all pre/post handlers in reverse order of
+// registration, collecting all errors encountered along the way.
+func (itr *IntegrationTestRunner) Stop() error {
+	ctx := context.Background()
+	var result *multierror.Error
+
+	if err := itr.binHandler.stop(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("stopping test binary failed: %w", err))
+	}
+
+	if err := itr.mergeCoverage(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("merging coverage failed: %w", err))
+	}
+
+	for i := len(itr.preHandlers) - 1; i >= 0; i-- {
+		h := itr.preHandlers[i]
+		if err := h.Stop(ctx); err != nil {
+			result = multierror.Append(result, fmt.Errorf("stopping %s failed: %w", h.Name(), err))
+		}
+	}
+
+	for i := len(itr.postHandlers) - 1; i >= 0; i-- {
+		h := itr.postHandlers[i]
+		if err := h.Stop(ctx); err != nil {
+			result = multierror.Append(result, fmt.Errorf("stopping %s failed: %w", h.Name(), err))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// Result returns the test binary's RunResult, populated once Stop has run.
+func (itr *IntegrationTestRunner) Result() RunResult {
+	return itr.binHandler.result
+}
+
+// InitAndRun runs Init, Run, the OptPprofCapture snapshot and Stop in order,
+// always attempting Stop even if an earlier step failed, and returns a
+// combined error if any of them failed (e.g. the wrapped tests failing and
+// Stop's coverage threshold check or handler teardown failing are both
+// surfaced, not just the first one).
+func (itr *IntegrationTestRunner) InitAndRun() error {
+	initErr := itr.Init()
+
+	var runErr, pprofErr error
+	if initErr == nil {
+		runErr = itr.Run()
+		pprofErr = itr.capturePprof(runErr)
+	}
+
+	stopErr := itr.Stop()
+
+	var result *multierror.Error
+	if initErr != nil {
+		result = multierror.Append(result, initErr)
+	}
+	if runErr != nil {
+		result = multierror.Append(result, runErr)
+	}
+	if pprofErr != nil {
+		result = multierror.Append(result, pprofErr)
+	}
+	if stopErr != nil {
+		result = multierror.Append(result, stopErr)
+	}
+
+	return result.ErrorOrNil()
+}
@@ -0,0 +1,256 @@
+package integrationtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// OptJSONOutput writes go-test-json-compatible test events to w, all at
+// once after m.Run() has returned.
+// Wrapping testing.M with OptTestMain normally loses `go test -json`
+// output because m.Run() just prints whatever format the binary was
+// invoked with; when this option is set, OptTestMain instead captures
+// m.Run()'s verbose output and converts it with `go tool test2json`.
+func OptJSONOutput(w io.Writer) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.jsonOutput = w
+		return nil
+	}
+}
+
+// OptJUnitOutput converts the same test event stream as OptJSONOutput
+// into a JUnit XML report written to path, with per-test duration,
+// failure messages and captured stdout, compatible with common CI
+// ingesters (Jenkins, GitLab).
+func OptJUnitOutput(path string) Opt {
+	return func(itr *IntegrationTestRunner) error {
+		itr.junitOutput = path
+		return nil
+	}
+}
+
+// testEvent mirrors the line-delimited JSON events `go tool test2json`
+// emits for each line of `go test -v` output.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// runWithJSONCapture runs m.Run() with os.Stdout redirected through `go
+// tool test2json`, then feeds the resulting events to OptJSONOutput and
+// OptJUnitOutput. `go test -json` gets its per-test events by running the
+// test binary with -test.v, but by the time TestMain runs it's too late to
+// add that flag to os.Args, so -test.v is forced in-process instead;
+// otherwise the non-verbose output has almost nothing for test2json to
+// convert and the resulting report would be effectively empty.
+func (itr *IntegrationTestRunner) runWithJSONCapture(m *testing.M) (int, error) {
+	if err := flag.Set("test.v", "true"); err != nil {
+		return 0, fmt.Errorf("forcing verbose test output failed: %w", err)
+	}
+
+	origStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("creating output pipe failed: %w", err)
+	}
+
+	os.Stdout = w
+	var jsonBuf bytes.Buffer
+	convDone := make(chan error, 1)
+	go func() {
+		convDone <- convertToTestJSON(r, &jsonBuf)
+	}()
+
+	code := m.Run()
+
+	os.Stdout = origStdout
+	_ = w.Close()
+	convErr := <-convDone
+	_ = r.Close()
+
+	if convErr != nil {
+		return code, fmt.Errorf("converting test output to JSON failed: %w", convErr)
+	}
+
+	if itr.jsonOutput != nil {
+		if _, err := itr.jsonOutput.Write(jsonBuf.Bytes()); err != nil {
+			return code, fmt.Errorf("writing JSON test output failed: %w", err)
+		}
+	}
+
+	if itr.junitOutput != "" {
+		events, err := parseTestEvents(jsonBuf.Bytes())
+		if err != nil {
+			return code, fmt.Errorf("parsing test JSON events failed: %w", err)
+		}
+
+		if err := writeJUnit(itr.junitOutput, events); err != nil {
+			return code, fmt.Errorf("writing JUnit report failed: %w", err)
+		}
+	}
+
+	return code, nil
+}
+
+// convertToTestJSON pipes r through the same test2json tool `go test
+// -json` uses internally, so the conversion stays correct across Go
+// versions without reimplementing its parsing rules.
+func convertToTestJSON(r io.Reader, w io.Writer) error {
+	cmd := exec.Command("go", "tool", "test2json", "-t") //nolint:gosec
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go tool test2json failed: %w", err)
+	}
+	return nil
+}
+
+func parseTestEvents(data []byte) ([]testEvent, error) {
+	var events []testEvent
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var ev testEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("parsing test event %q failed: %w", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning test output failed: %w", err)
+	}
+	return events, nil
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitSkipped struct{}
+
+// buildJUnit groups a flat test2json event stream by package and test
+// name into the nested testsuite/testcase structure JUnit expects.
+func buildJUnit(events []testEvent) junitTestSuites {
+	var order []string
+	suites := map[string]*junitTestSuite{}
+	output := map[string]*strings.Builder{}
+
+	suite := func(pkg string) *junitTestSuite {
+		s, ok := suites[pkg]
+		if !ok {
+			s = &junitTestSuite{Name: pkg}
+			suites[pkg] = s
+			order = append(order, pkg)
+		}
+		return s
+	}
+
+	for _, ev := range events {
+		if ev.Test == "" {
+			if ev.Action == "pass" || ev.Action == "fail" {
+				suite(ev.Package).Time = fmt.Sprintf("%.3f", ev.Elapsed)
+			}
+			continue
+		}
+
+		key := ev.Package + "/" + ev.Test
+		switch ev.Action {
+		case "output":
+			b, ok := output[key]
+			if !ok {
+				b = &strings.Builder{}
+				output[key] = b
+			}
+			b.WriteString(ev.Output)
+
+		case "pass", "fail", "skip":
+			tc := junitTestCase{
+				ClassName: ev.Package,
+				Name:      ev.Test,
+				Time:      fmt.Sprintf("%.3f", ev.Elapsed),
+			}
+			if b, ok := output[key]; ok {
+				tc.SystemOut = b.String()
+			}
+
+			s := suite(ev.Package)
+			switch ev.Action {
+			case "fail":
+				tc.Failure = &junitFailure{Message: "test failed", Content: tc.SystemOut}
+				s.Failures++
+			case "skip":
+				tc.Skipped = &junitSkipped{}
+				s.Skipped++
+			}
+			s.Tests++
+			s.Cases = append(s.Cases, tc)
+		}
+	}
+
+	result := junitTestSuites{}
+	for _, pkg := range order {
+		result.Suites = append(result.Suites, *suites[pkg])
+	}
+	return result
+}
+
+func writeJUnit(path string, events []testEvent) error {
+	report, err := xml.MarshalIndent(buildJUnit(events), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling JUnit XML failed: %w", err)
+	}
+
+	content := append([]byte(xml.Header), report...)
+	if err := os.WriteFile(path, content, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("writing %s failed: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package integrationtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	data := []byte(`{"Action":"run","Package":"pkg","Test":"TestFoo"}
+{"Action":"output","Package":"pkg","Test":"TestFoo","Output":"ok\n"}
+{"Action":"pass","Package":"pkg","Test":"TestFoo","Elapsed":0.01}
+`)
+
+	events, err := parseTestEvents(data)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "run", events[0].Action)
+	assert.Equal(t, "output", events[1].Action)
+	assert.Equal(t, "ok\n", events[1].Output)
+	assert.Equal(t, "pass", events[2].Action)
+	assert.InDelta(t, 0.01, events[2].Elapsed, 0.0001)
+}
+
+func TestParseTestEventsInvalidJSON(t *testing.T) {
+	_, err := parseTestEvents([]byte("not json\n"))
+	assert.Error(t, err)
+}
+
+func TestBuildJUnit(t *testing.T) {
+	events := []testEvent{
+		{Action: "run", Package: "pkg", Test: "TestPass"},
+		{Action: "output", Package: "pkg", Test: "TestPass", Output: "ok\n"},
+		{Action: "pass", Package: "pkg", Test: "TestPass", Elapsed: 0.01},
+		{Action: "run", Package: "pkg", Test: "TestFail"},
+		{Action: "output", Package: "pkg", Test: "TestFail", Output: "boom\n"},
+		{Action: "fail", Package: "pkg", Test: "TestFail", Elapsed: 0.02},
+		{Action: "run", Package: "pkg", Test: "TestSkip"},
+		{Action: "skip", Package: "pkg", Test: "TestSkip"},
+		{Action: "pass", Package: "pkg", Elapsed: 0.03},
+	}
+
+	suites := buildJUnit(events)
+	require.Len(t, suites.Suites, 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, "pkg", suite.Name)
+	assert.Equal(t, 3, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Equal(t, 1, suite.Skipped)
+	assert.Equal(t, "0.030", suite.Time)
+	require.Len(t, suite.Cases, 3)
+
+	assert.Equal(t, "TestPass", suite.Cases[0].Name)
+	assert.Nil(t, suite.Cases[0].Failure)
+	assert.Equal(t, "ok\n", suite.Cases[0].SystemOut)
+
+	assert.Equal(t, "TestFail", suite.Cases[1].Name)
+	require.NotNil(t, suite.Cases[1].Failure)
+	assert.Equal(t, "boom\n", suite.Cases[1].Failure.Content)
+
+	assert.Equal(t, "TestSkip", suite.Cases[2].Name)
+	assert.NotNil(t, suite.Cases[2].Skipped)
+}
+
+func TestBuildJUnitMultiplePackagesPreserveOrder(t *testing.T) {
+	events := []testEvent{
+		{Action: "run", Package: "pkg/b", Test: "TestB"},
+		{Action: "pass", Package: "pkg/b", Test: "TestB", Elapsed: 0.02},
+		{Action: "pass", Package: "pkg/b", Elapsed: 0.02},
+		{Action: "run", Package: "pkg/a", Test: "TestA"},
+		{Action: "pass", Package: "pkg/a", Test: "TestA", Elapsed: 0.01},
+		{Action: "pass", Package: "pkg/a", Elapsed: 0.01},
+	}
+
+	suites := buildJUnit(events)
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, "pkg/b", suites.Suites[0].Name)
+	assert.Equal(t, "pkg/a", suites.Suites[1].Name)
+}
+
+func TestBuildJUnitEmpty(t *testing.T) {
+	suites := buildJUnit(nil)
+	assert.Empty(t, suites.Suites)
+}
@@ -0,0 +1,147 @@
+package pprof
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// defaultDuration is how long the "profile" (CPU) and "trace" endpoints
+// are sampled for when CaptureOpts.Duration is left at zero.
+const defaultDuration = 10 * time.Second
+
+// samples are the net/http/pprof endpoints captured by Capture that `go
+// tool pprof` can analyze directly; trace is handled separately since
+// pprof doesn't understand it.
+var samples = []string{"heap", "goroutine", "allocs", "mutex", "block"}
+
+// CaptureOpts configures Capture.
+type CaptureOpts struct {
+	// Duration bounds how long the "profile" and "trace" endpoints are
+	// sampled for. Defaults to 10s if zero.
+	Duration time.Duration
+
+	// Dir, if set, writes each fetched profile to "<Dir>/<name>.pprof"
+	// (or ".trace" for the execution trace), plus, for all but the trace, a
+	// "<name>.txt" `go tool pprof -top` summary next to it. Created if it
+	// doesn't exist.
+	Dir string
+}
+
+// Capture fetches heap, goroutine, allocs, mutex and block profiles plus
+// a CPU profile and an execution trace from a running server's
+// net/http/pprof endpoints at baseURL (e.g. "http://127.0.0.1:8080"),
+// returning each as raw bytes keyed by name ("heap", "profile",
+// "trace", ...). If opts.Dir is set, each profile is also written to
+// disk, alongside a `go tool pprof -top` text summary for all but the
+// trace.
+func Capture(ctx context.Context, baseURL string, opts CaptureOpts) (map[string][]byte, error) {
+	duration := opts.Duration
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+
+	result := make(map[string][]byte, len(samples)+2)
+
+	for _, name := range samples {
+		data, err := fetch(ctx, baseURL+"/debug/pprof/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("capturing %s profile failed: %w", name, err)
+		}
+		result[name] = data
+	}
+
+	profile, err := fetch(ctx, fmt.Sprintf("%s/debug/pprof/profile?seconds=%d", baseURL, int(duration.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("capturing profile failed: %w", err)
+	}
+	result["profile"] = profile
+
+	trace, err := fetch(ctx, fmt.Sprintf("%s/debug/pprof/trace?seconds=%d", baseURL, int(duration.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("capturing trace failed: %w", err)
+	}
+	result["trace"] = trace
+
+	if opts.Dir != "" {
+		if err := writeProfiles(opts.Dir, result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s failed: %w", url, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s failed: %w", url, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s failed: %s", url, resp.Status)
+	}
+	return data, nil
+}
+
+// writeProfiles writes each captured profile to dir, named by its pprof
+// endpoint, and appends a `go tool pprof -top` text summary for
+// everything but the trace, which pprof can't analyze.
+func writeProfiles(dir string, profiles map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating %s failed: %w", dir, err)
+	}
+
+	for name, data := range profiles {
+		ext := "pprof"
+		if name == "trace" {
+			ext = "trace"
+		}
+
+		path := filepath.Join(dir, name+"."+ext)
+		if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("writing %s failed: %w", path, err)
+		}
+
+		if name == "trace" {
+			continue
+		}
+
+		summary, err := analyze(path)
+		if err != nil {
+			return fmt.Errorf("analyzing %s failed: %w", path, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name+".txt"), summary, 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("writing %s summary failed: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// analyze runs `go tool pprof -top` against a captured profile and
+// returns its text summary.
+func analyze(profilePath string) ([]byte, error) {
+	out, err := exec.Command("go", "tool", "pprof", "-top", profilePath).Output() //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("go tool pprof -top failed: %w", err)
+	}
+	return out, nil
+}